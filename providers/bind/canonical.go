@@ -0,0 +1,99 @@
+// Deterministic ("canonical") output mode, for zones that need to compare
+// byte-identical across runs regardless of input casing or order -- e.g.
+// git-tracked zone snapshots and diff-based change review in CI.
+package bind
+
+import (
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// WriteZoneFileCanonical writes a zone file that is byte-identical for any
+// two logically-equal zones: owner names and domain-name RDATA are
+// lowercased, A/AAAA addresses are normalized through netip.Addr so
+// equivalent textual forms collapse, and ties that WriteZoneFile leaves to
+// rr.String() comparison are broken deterministically.
+func WriteZoneFileCanonical(w io.Writer, records []dns.RR, origin string, defaultTtl uint32) error {
+	canon := make([]dns.RR, len(records))
+	for i, rr := range records {
+		canon[i] = canonicalizeRR(rr)
+	}
+
+	z := &canonicalZoneData{zoneGenData{
+		Origin:     strings.ToLower(origin),
+		DefaultTtl: defaultTtl,
+		Records:    canon,
+	}}
+	sort.Sort(z)
+	return z.printZoneFile(w)
+}
+
+// canonicalZoneData reuses zoneGenData's fields and printing but replaces
+// Less's final tiebreaker with a fully deterministic comparison, since
+// WriteZoneFileCanonical needs a total order, not just "good enough to look
+// pretty".
+type canonicalZoneData struct {
+	zoneGenData
+}
+
+func (z *canonicalZoneData) Less(i, j int) bool {
+	a, b := z.Records[i], z.Records[j]
+	if (&z.zoneGenData).Less(i, j) {
+		return true
+	}
+	if (&z.zoneGenData).Less(j, i) {
+		return false
+	}
+	// Same owner, type, and (for A/MX) the fields zoneGenData.Less already
+	// orders on -- fall back to the full record text (its names already
+	// lowercased by canonicalizeRR) so the order no longer depends on
+	// sort.Sort's (unstable) internal behavior.
+	return a.String() < b.String()
+}
+
+// canonicalizeRR returns a copy of rr with its owner name and any
+// domain-name RDATA lowercased, and A/AAAA addresses normalized through
+// netip.Addr.
+func canonicalizeRR(rr dns.RR) dns.RR {
+	rr = dns.Copy(rr)
+	rr.Header().Name = strings.ToLower(rr.Header().Name)
+
+	switch t := rr.(type) {
+	case *dns.A:
+		t.A = canonicalizeIP(t.A)
+	case *dns.AAAA:
+		t.AAAA = canonicalizeIP(t.AAAA)
+	case *dns.CNAME:
+		t.Target = strings.ToLower(t.Target)
+	case *dns.NS:
+		t.Ns = strings.ToLower(t.Ns)
+	case *dns.PTR:
+		t.Ptr = strings.ToLower(t.Ptr)
+	case *dns.MX:
+		t.Mx = strings.ToLower(t.Mx)
+	case *dns.SRV:
+		t.Target = strings.ToLower(t.Target)
+	case *dns.SOA:
+		t.Ns = strings.ToLower(t.Ns)
+		t.Mbox = strings.ToLower(t.Mbox)
+	case *dns.NAPTR:
+		t.Replacement = strings.ToLower(t.Replacement)
+	}
+	return rr
+}
+
+// canonicalizeIP round-trips ip through netip.Addr so equivalent textual
+// forms (e.g. "192.168.001.001" vs "192.168.1.1") collapse to the same
+// canonical net.IP.
+func canonicalizeIP(ip net.IP) net.IP {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ip
+	}
+	return net.IP(addr.Unmap().AsSlice())
+}
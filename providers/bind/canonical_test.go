@@ -0,0 +1,60 @@
+package bind
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestWriteZoneFileCanonicalLowercasesAndNormalizesIPs(t *testing.T) {
+	paddedA := &dns.A{
+		Hdr: dns.RR_Header{Name: "WWW.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+		A:   net.IPv4(192, 168, 1, 1).To16(), // exercises netip's Unmap(), like a v4-in-v6 input would
+	}
+	records := []dns.RR{
+		mustParseRR(t, "EXAMPLE.COM. 3600 IN SOA NS1.EXAMPLE.COM. hostmaster.example.com. 1 3600 600 604800 3600"),
+		paddedA,
+		mustParseRR(t, "sip.example.com. 300 IN NAPTR 100 10 \"S\" \"SIP+D2U\" \"\" SIPSERVER.EXAMPLE.COM."),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteZoneFileCanonical(&buf, records, "EXAMPLE.COM", 3600); err != nil {
+		t.Fatalf("WriteZoneFileCanonical: %v", err)
+	}
+	out := buf.String()
+
+	if bytes.Contains([]byte(out), []byte("WWW")) || bytes.Contains([]byte(out), []byte("EXAMPLE.COM.")) {
+		t.Errorf("canonical output still contains uppercase owner/domain names:\n%s", out)
+	}
+	if !bytes.Contains([]byte(out), []byte("192.168.1.1")) || bytes.Contains([]byte(out), []byte("::ffff:")) {
+		t.Errorf("canonical output did not normalize the v4-in-v6 address to plain IPv4:\n%s", out)
+	}
+	if bytes.Contains([]byte(out), []byte("SIPSERVER")) {
+		t.Errorf("canonical output left the NAPTR replacement field uppercase:\n%s", out)
+	}
+}
+
+func TestWriteZoneFileCanonicalIsByteIdenticalRegardlessOfInputOrder(t *testing.T) {
+	a := []dns.RR{
+		mustParseRR(t, "www.example.com. 300 IN A 10.0.0.1"),
+		mustParseRR(t, "MAIL.example.com. 300 IN A 10.0.0.2"),
+	}
+	b := []dns.RR{
+		mustParseRR(t, "mail.EXAMPLE.com. 300 IN A 10.0.0.2"),
+		mustParseRR(t, "WWW.example.com. 300 IN A 10.0.0.1"),
+	}
+
+	var bufA, bufB bytes.Buffer
+	if err := WriteZoneFileCanonical(&bufA, a, "example.com", 300); err != nil {
+		t.Fatalf("WriteZoneFileCanonical(a): %v", err)
+	}
+	if err := WriteZoneFileCanonical(&bufB, b, "example.com", 300); err != nil {
+		t.Fatalf("WriteZoneFileCanonical(b): %v", err)
+	}
+
+	if bufA.String() != bufB.String() {
+		t.Errorf("canonical output differs for logically-equal zones:\n--- a ---\n%s\n--- b ---\n%s", bufA.String(), bufB.String())
+	}
+}
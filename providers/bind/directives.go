@@ -0,0 +1,254 @@
+// RFC 1035 master-file directive emission: $ORIGIN, $INCLUDE, and
+// $GENERATE, layered on top of WriteZoneFile's pretty-printing. These are
+// widely supported by BIND/NSD/Knot and let generated zones stay small and
+// readable even when they cover subzones or provisioning-style sequences.
+package bind
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/miekg/dns/dnsutil"
+)
+
+// ZoneGenOptions controls which master-file directives WriteZoneFileOpts
+// emits in addition to the usual $TTL line.
+type ZoneGenOptions struct {
+	// Subzones maps a subzone name, relative to origin (e.g. "sub" for
+	// "sub.example.com."), to a filename. Records owned by that subzone are
+	// pulled out of the generated zone:
+	//   - if filename is non-empty, they're replaced by a single $INCLUDE
+	//     directive pointing at that file.
+	//   - if filename is empty, they're printed inline, bracketed by an
+	//     $ORIGIN directive that shortens their names, then an $ORIGIN
+	//     reverting back to the parent zone.
+	Subzones map[string]string
+
+	// Generate collapses runs of sequentially-numbered A records (e.g.
+	// host1..host100 -> 10.0.0.1..10.0.0.100) into $GENERATE directives.
+	Generate bool
+}
+
+// WriteZoneFileOpts is WriteZoneFile with support for the $ORIGIN,
+// $INCLUDE, and $GENERATE directives controlled by opts.
+func WriteZoneFileOpts(w io.Writer, records []dns.RR, origin string, defaultTtl uint32, opts *ZoneGenOptions) error {
+	if opts == nil {
+		opts = &ZoneGenOptions{}
+	}
+
+	// Split subzones out first, so a $GENERATE scan never pulls a delegated
+	// subzone's A records into the parent zone's output.
+	main, subzoneRecords := splitSubzones(records, origin, opts.Subzones)
+
+	var generateLines []string
+	if opts.Generate {
+		generateLines, main = extractGenerateSequences(main, origin, defaultTtl)
+	}
+
+	z := sortedRecords(main, origin, defaultTtl)
+	fmt.Fprintln(w, "$TTL", defaultTtl)
+	for _, line := range generateLines {
+		fmt.Fprintln(w, line)
+	}
+
+	if err := z.printRecords(w); err != nil {
+		return err
+	}
+
+	// Subzones are emitted in name order so output stays deterministic.
+	subNames := make([]string, 0, len(subzoneRecords))
+	for sub := range subzoneRecords {
+		subNames = append(subNames, sub)
+	}
+	sort.Strings(subNames)
+
+	for _, sub := range subNames {
+		filename := opts.Subzones[sub]
+		subOrigin := sub + "." + origin
+		if filename != "" {
+			fmt.Fprintf(w, "$INCLUDE %s %s.\n", filename, subOrigin)
+			continue
+		}
+
+		subRecords := subzoneRecords[sub]
+		var subGenerateLines []string
+		if opts.Generate {
+			subGenerateLines, subRecords = extractGenerateSequences(subRecords, subOrigin, defaultTtl)
+		}
+
+		fmt.Fprintf(w, "$ORIGIN %s.\n", subOrigin)
+		fmt.Fprintln(w, "$TTL", defaultTtl)
+		for _, line := range subGenerateLines {
+			fmt.Fprintln(w, line)
+		}
+		sz := sortedRecords(subRecords, subOrigin, defaultTtl)
+		if err := sz.printRecords(w); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "$ORIGIN %s.\n", origin)
+	}
+
+	return nil
+}
+
+// splitSubzones partitions records into the ones that stay in the parent
+// zone and the ones owned by a configured subzone, keyed by subzone name.
+func splitSubzones(records []dns.RR, origin string, subzones map[string]string) (main []dns.RR, bySubzone map[string][]dns.RR) {
+	bySubzone = map[string][]dns.RR{}
+	if len(subzones) == 0 {
+		return records, bySubzone
+	}
+	for _, rr := range records {
+		nameShort := dnsutil.TrimDomainName(rr.Header().Name, origin)
+		if sub, ok := ownerSubzone(nameShort, subzones); ok {
+			bySubzone[sub] = append(bySubzone[sub], rr)
+			continue
+		}
+		main = append(main, rr)
+	}
+	return main, bySubzone
+}
+
+// ownerSubzone reports whether nameShort falls under one of the configured
+// subzone names (either equal to it, or a descendant of it).
+func ownerSubzone(nameShort string, subzones map[string]string) (string, bool) {
+	for sub := range subzones {
+		if nameShort == sub || strings.HasSuffix(nameShort, "."+sub) {
+			return sub, true
+		}
+	}
+	return "", false
+}
+
+// generateHostRE matches an owner name ending in a decimal index, e.g.
+// "host1" -> prefix "host", index 1.
+var generateHostRE = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// extractGenerateSequences pulls runs of 3 or more sequentially-numbered A
+// records with sequentially-increasing IPv4 addresses out of records,
+// returning a $GENERATE line for each run plus the records that weren't
+// part of one. Only records at defaultTtl are considered: the emitted
+// $GENERATE line carries no ttl field, so collapsing a record with a
+// different TTL would silently change it on reload.
+func extractGenerateSequences(records []dns.RR, origin string, defaultTtl uint32) ([]string, []dns.RR) {
+	type candidate struct {
+		rr    *dns.A
+		index int
+		// width is the number of digits in the owner name's numeric suffix
+		// as written (e.g. 2 for "host01"), which may exceed the natural,
+		// unpadded width of index (e.g. 1 for 1). A run may only collapse
+		// into one $GENERATE line if every member shares the same width,
+		// since BIND's lhs applies a single zero-pad width to the whole
+		// range.
+		width int
+	}
+
+	byPrefix := map[string][]candidate{}
+	other := make([]dns.RR, 0, len(records))
+
+	for _, rr := range records {
+		a, ok := rr.(*dns.A)
+		if !ok || a.Header().Ttl != defaultTtl {
+			other = append(other, rr)
+			continue
+		}
+		nameShort := dnsutil.TrimDomainName(a.Header().Name, origin)
+		m := generateHostRE.FindStringSubmatch(nameShort)
+		if m == nil || a.A.To4() == nil {
+			other = append(other, rr)
+			continue
+		}
+		idx, err := strconv.Atoi(m[2])
+		if err != nil {
+			other = append(other, rr)
+			continue
+		}
+		byPrefix[m[1]] = append(byPrefix[m[1]], candidate{rr: a, index: idx, width: len(m[2])})
+	}
+
+	// Prefixes are visited in sorted order so two calls on identical input
+	// always emit $GENERATE lines in the same order, instead of depending on
+	// Go's randomized map iteration.
+	prefixes := make([]string, 0, len(byPrefix))
+	for prefix := range byPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var lines []string
+	consumed := map[*dns.A]bool{}
+	for _, prefix := range prefixes {
+		cands := byPrefix[prefix]
+		sort.Slice(cands, func(i, j int) bool { return cands[i].index < cands[j].index })
+		for start := 0; start < len(cands); {
+			end := start
+			for end+1 < len(cands) &&
+				cands[end+1].index == cands[end].index+1 &&
+				cands[end+1].width == cands[end].width &&
+				sameGenerateRun(cands[end].rr.A, cands[end+1].rr.A) {
+				end++
+			}
+			if end-start >= 2 { // run of >= 3 hosts
+				first, last := cands[start], cands[end]
+				padded := false
+				for i := start; i <= end; i++ {
+					if len(strconv.Itoa(cands[i].index)) < cands[i].width {
+						padded = true
+						break
+					}
+				}
+				lhs := prefix + "$"
+				if padded {
+					// BIND zero-pads the iterator to width using the
+					// ${offset,width,base} form; a bare "$" would instead
+					// expand back to the unpadded decimal, turning e.g.
+					// "host01" into "host1" on reload.
+					lhs = fmt.Sprintf("%s${0,%d,d}", prefix, first.width)
+				}
+				lines = append(lines, fmt.Sprintf("$GENERATE %d-%d %s A %s",
+					first.index, last.index, lhs, generateIPTemplate(first.rr.A, first.index)))
+				for i := start; i <= end; i++ {
+					consumed[cands[i].rr] = true
+				}
+			}
+			start = end + 1
+		}
+		for _, c := range cands {
+			if !consumed[c.rr] {
+				other = append(other, c.rr)
+			}
+		}
+	}
+
+	return lines, other
+}
+
+// sameGenerateRun reports whether b is the next address after a in a
+// $GENERATE-collapsible run: both in the same /24 and b's last octet is
+// exactly one more than a's. Checking only the last octet let hosts from
+// unrelated /24s (e.g. 10.0.0.1 and 10.0.1.2) collapse into a single,
+// wrong $GENERATE template, so the first three octets must match too.
+func sameGenerateRun(a, b net.IP) bool {
+	a4, b4 := a.To4(), b.To4()
+	return a4[0] == b4[0] && a4[1] == b4[1] && a4[2] == b4[2] && int(b4[3])-int(a4[3]) == 1
+}
+
+// generateIPTemplate renders the first address of a $GENERATE run as a
+// template, with its last octet replaced by the BIND "$" placeholder
+// offset by the run's starting index. A non-zero offset must be braced
+// (e.g. "${5}", or "${-5}" for a negative one) -- BIND only recognizes a
+// bare "$+N" as the iterator followed by literal text, not an offset.
+func generateIPTemplate(ip net.IP, startIndex int) string {
+	ip4 := ip.To4()
+	base := int(ip4[3]) - startIndex
+	if base == 0 {
+		return fmt.Sprintf("%d.%d.%d.$", ip4[0], ip4[1], ip4[2])
+	}
+	return fmt.Sprintf("%d.%d.%d.${%d}", ip4[0], ip4[1], ip4[2], base)
+}
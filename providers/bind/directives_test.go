@@ -0,0 +1,159 @@
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// generateHostRecords builds n sequential A records named prefix+index
+// (e.g. "host1", or zero-padded to width via printf "%0*d" when width > 0),
+// all with TTL 300, starting at startIP and incrementing the last octet.
+func generateHostRecords(prefix string, start, n, width int, startIP net.IP) []dns.RR {
+	records := make([]dns.RR, n)
+	ip4 := startIP.To4()
+	for i := 0; i < n; i++ {
+		idx := start + i
+		name := prefix + strconv.Itoa(idx)
+		if width > 0 {
+			name = prefix + fmt.Sprintf("%0*d", width, idx)
+		}
+		records[i] = &dns.A{
+			Hdr: dns.RR_Header{Name: name + ".example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.IPv4(ip4[0], ip4[1], ip4[2], ip4[3]+byte(i)),
+		}
+	}
+	return records
+}
+
+func TestExtractGenerateSequencesBasic(t *testing.T) {
+	records := generateHostRecords("host", 1, 3, 0, net.IPv4(10, 0, 0, 1))
+	lines, rest := extractGenerateSequences(records, "example.com", 300)
+
+	if len(rest) != 0 {
+		t.Fatalf("got %d leftover records, want 0: %v", len(rest), rest)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("got %d $GENERATE lines, want 1: %v", len(lines), lines)
+	}
+	want := "$GENERATE 1-3 host$ A 10.0.0.$"
+	if lines[0] != want {
+		t.Errorf("line = %q, want %q", lines[0], want)
+	}
+}
+
+func TestExtractGenerateSequencesZeroPadded(t *testing.T) {
+	// host01, host02, host03 must not collapse into a plain "host$" line --
+	// that would expand back to host1/host2/host3 on reload, renaming every
+	// host.
+	records := generateHostRecords("host", 1, 3, 2, net.IPv4(10, 0, 0, 1))
+	lines, rest := extractGenerateSequences(records, "example.com", 300)
+
+	if len(rest) != 0 {
+		t.Fatalf("got %d leftover records, want 0: %v", len(rest), rest)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("got %d $GENERATE lines, want 1: %v", len(lines), lines)
+	}
+	want := "$GENERATE 1-3 host${0,2,d} A 10.0.0.$"
+	if lines[0] != want {
+		t.Errorf("line = %q, want %q", lines[0], want)
+	}
+
+	// The lhs must carry the width-qualified placeholder: that is what makes
+	// BIND zero-pad the iterator back to "host01".."host03" rather than
+	// "host1".."host3".
+	if !strings.Contains(lines[0], "${0,2,d}") {
+		t.Fatalf("expected a width-qualified lhs placeholder, got %q", lines[0])
+	}
+}
+
+func TestExtractGenerateSequencesMixedWidthNotCollapsed(t *testing.T) {
+	// host9, host10, host11: the written suffix width is not uniform (1, 2,
+	// 2 digits). Only members sharing one written width join a run, so
+	// host9 stays apart, and host10..host11 alone is shorter than the
+	// 3-host minimum: nothing collapses.
+	records := []dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Name: "host9.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.IPv4(10, 0, 0, 9)},
+		&dns.A{Hdr: dns.RR_Header{Name: "host10.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.IPv4(10, 0, 0, 10)},
+		&dns.A{Hdr: dns.RR_Header{Name: "host11.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.IPv4(10, 0, 0, 11)},
+	}
+	lines, rest := extractGenerateSequences(records, "example.com", 300)
+
+	if len(lines) != 0 {
+		t.Errorf("got %d $GENERATE lines, want 0 (mixed-width run must not collapse): %v", len(lines), lines)
+	}
+	if len(rest) != 3 {
+		t.Errorf("got %d leftover records, want 3: %v", len(rest), rest)
+	}
+}
+
+func TestWriteZoneFileOptsInclude(t *testing.T) {
+	records := []dns.RR{
+		mustParseRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 3600"),
+		mustParseRR(t, "www.example.com. 300 IN A 10.0.0.1"),
+		mustParseRR(t, "host1.sub.example.com. 300 IN A 10.0.1.1"),
+	}
+	opts := &ZoneGenOptions{Subzones: map[string]string{"sub": "sub.zone"}}
+
+	var buf bytes.Buffer
+	if err := WriteZoneFileOpts(&buf, records, "example.com", 300, opts); err != nil {
+		t.Fatalf("WriteZoneFileOpts: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, "$INCLUDE sub.zone sub.example.com.\n") {
+		t.Errorf("output missing $INCLUDE directive:\n%s", out)
+	}
+	if strings.Contains(out, "host1") {
+		t.Errorf("subzone record leaked into the parent zone output:\n%s", out)
+	}
+}
+
+func TestWriteZoneFileOptsInlineOriginSubzoneRoundTrips(t *testing.T) {
+	records := []dns.RR{
+		mustParseRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 3600"),
+		mustParseRR(t, "www.example.com. 300 IN A 10.0.0.1"),
+		mustParseRR(t, "host1.sub.example.com. 300 IN A 10.0.1.1"),
+		mustParseRR(t, "host2.sub.example.com. 300 IN A 10.0.1.2"),
+	}
+	// No filename: the subzone is printed inline, bracketed by $ORIGIN.
+	opts := &ZoneGenOptions{Subzones: map[string]string{"sub": ""}}
+
+	var buf bytes.Buffer
+	if err := WriteZoneFileOpts(&buf, records, "example.com", 300, opts); err != nil {
+		t.Fatalf("WriteZoneFileOpts: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, "$ORIGIN sub.example.com.\n") {
+		t.Errorf("output missing inline $ORIGIN for subzone:\n%s", out)
+	}
+	if !strings.Contains(out, "$ORIGIN example.com.\n") {
+		t.Errorf("output missing $ORIGIN reverting back to the parent zone:\n%s", out)
+	}
+
+	zp := dns.NewZoneParser(strings.NewReader(out), "example.com.", "")
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("re-parsing zone with inline subzone: %v\n%s", err, out)
+	}
+
+	names := map[string]bool{}
+	for _, rr := range rrs {
+		names[rr.Header().Name] = true
+	}
+	for _, want := range []string{"www.example.com.", "host1.sub.example.com.", "host2.sub.example.com."} {
+		if !names[want] {
+			t.Errorf("owner name %q missing after round-trip; got %v", want, names)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// DNSSEC signing support for generated zonefiles.
+package bind
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DNSSECKey pairs a DNSKEY record with the crypto.Signer that holds its
+// private half, so a SignatureConfig can carry multiple signing keys (e.g.
+// a KSK and a ZSK) without losing track of which signer goes with which key.
+type DNSSECKey struct {
+	DNSKEY *dns.DNSKEY
+	Signer crypto.Signer
+}
+
+// SignatureConfig controls how WriteSignedZoneFile signs a zone. It mirrors
+// the inception/expiration/signer-name/key-set shape of miekg/dns's Zone
+// signing helpers, plus the NSEC3 options (salt, iterations, opt-out) that
+// aren't part of a bare RRSIG.
+type SignatureConfig struct {
+	Inception  time.Time
+	Expiration time.Time
+	SignerName string // zone apex, fully qualified, e.g. "example.com."
+	Keys       []*DNSSECKey
+
+	// DefaultSigTTL is used as the RRSIG's Header.Ttl when the covered
+	// RRset's own TTL is zero.
+	DefaultSigTTL uint32
+
+	// NSEC3 selects NSEC3 instead of NSEC for the authenticated-denial chain.
+	NSEC3      bool
+	Salt       string
+	Iterations uint16
+	OptOut     bool
+}
+
+// WriteSignedZoneFile writes a beautifully formatted, DNSSEC-signed zone
+// file: it generates RRSIGs for every RRset and an NSEC or NSEC3 chain
+// covering the authoritative owner names, then prints everything with each
+// RRSIG placed immediately after the RRset it covers.
+func WriteSignedZoneFile(w io.Writer, records []dns.RR, origin string, defaultTtl uint32, cfg *SignatureConfig) error {
+	if cfg == nil || len(cfg.Keys) == 0 {
+		return fmt.Errorf("WriteSignedZoneFile: SignatureConfig must specify at least one signing key")
+	}
+
+	z := &zoneGenData{
+		Origin:     origin,
+		DefaultTtl: defaultTtl,
+		Records:    append([]dns.RR{}, records...),
+	}
+
+	for _, k := range cfg.Keys {
+		z.Records = append(z.Records, k.DNSKEY)
+	}
+
+	rrsigs, owners, typesByOwner, err := signRRsets(z.Records, cfg)
+	if err != nil {
+		return err
+	}
+	z.Records = append(z.Records, rrsigs...)
+
+	var chain []dns.RR
+	if cfg.NSEC3 {
+		chain = buildNSEC3Chain(owners, typesByOwner, origin, cfg)
+	} else {
+		chain = buildNSECChain(owners, typesByOwner)
+	}
+	z.Records = append(z.Records, chain...)
+
+	chainSigs, _, _, err := signRRsets(chain, cfg)
+	if err != nil {
+		return err
+	}
+	z.Records = append(z.Records, chainSigs...)
+
+	// A plain zoneGenData sort would clump every RRSIG together (they all
+	// share Header().Rrtype == TypeRRSIG), printed as one block rather than
+	// immediately after the RRset each one covers. signedZoneData sorts by
+	// the covered type instead, so each RRSIG lands right after its RRset.
+	sz := &signedZoneData{*z}
+	sort.Sort(sz)
+	return sz.printZoneFile(w)
+}
+
+// signedZoneData sorts like zoneGenData -- same label order, same
+// SOA/NS-first and MX/A tiebreaks -- except it treats an RRSIG as if it
+// were a record of the type it covers, and places it immediately after the
+// (non-RRSIG) records of that type at the same owner name.
+type signedZoneData struct {
+	zoneGenData
+}
+
+func (z *signedZoneData) Less(i, j int) bool {
+	a, b := z.Records[i], z.Records[j]
+
+	keyA, keyB := zoneOwnerKey(a, z.Origin), zoneOwnerKey(b, z.Origin)
+	if keyA != keyB {
+		return zoneLabelLess(keyA, keyB)
+	}
+
+	typeA, typeB := coveredOrOwnType(a), coveredOrOwnType(b)
+	if typeA != typeB {
+		return zoneRrtypeLess(typeA, typeB)
+	}
+
+	rsigA, rsigB := a.Header().Rrtype == dns.TypeRRSIG, b.Header().Rrtype == dns.TypeRRSIG
+	if rsigA != rsigB {
+		// The RRset itself (rsig==false) sorts before the RRSIG(s) covering
+		// it, so "immediately after the RRset it covers" holds.
+		return rsigB
+	}
+	if rsigA {
+		// Same RRset signed by more than one key: tiebreak on key tag so
+		// the order is still deterministic.
+		return a.(*dns.RRSIG).KeyTag < b.(*dns.RRSIG).KeyTag
+	}
+
+	if typeA == dns.TypeA {
+		ta, tb := a.(*dns.A), b.(*dns.A)
+		ipa, ipb := ta.A.To4(), tb.A.To4()
+		if ipa == nil || ipb == nil {
+			log.Fatalf("should not happen: IPs are not 4 bytes: %#v %#v", ta, tb)
+		}
+		return bytes.Compare(ipa, ipb) == -1
+	}
+	if typeA == dns.TypeMX {
+		ta, tb := a.(*dns.MX), b.(*dns.MX)
+		return ta.Preference < tb.Preference
+	}
+	return a.String() < b.String()
+}
+
+// coveredOrOwnType returns an RRSIG's TypeCovered, or rr's own type for
+// anything else, so RRSIGs sort alongside the RRset they cover.
+func coveredOrOwnType(rr dns.RR) uint16 {
+	if sig, ok := rr.(*dns.RRSIG); ok {
+		return sig.TypeCovered
+	}
+	return rr.Header().Rrtype
+}
+
+// rrsetKey identifies an RRset: same owner name and type.
+type rrsetKey struct {
+	name   string
+	rrtype uint16
+}
+
+// signRRsets groups records into RRsets (same owner name + type), signs
+// each RRset with every key in cfg.Keys, and returns the generated RRSIGs,
+// the deterministically-ordered list of distinct owner names seen, and the
+// set of types present at each owner name -- everything buildNSECChain and
+// buildNSEC3Chain need to synthesize the denial-of-existence chain.
+func signRRsets(records []dns.RR, cfg *SignatureConfig) ([]dns.RR, []string, map[string][]uint16, error) {
+	sets := map[rrsetKey][]dns.RR{}
+	var order []rrsetKey
+	var owners []string
+	seenOwner := map[string]bool{}
+	typesByOwner := map[string][]uint16{}
+
+	for _, rr := range records {
+		if rr.Header().Rrtype == dns.TypeRRSIG {
+			continue
+		}
+		k := rrsetKey{name: rr.Header().Name, rrtype: rr.Header().Rrtype}
+		if _, ok := sets[k]; !ok {
+			order = append(order, k)
+			typesByOwner[k.name] = append(typesByOwner[k.name], k.rrtype)
+		}
+		sets[k] = append(sets[k], rr)
+		if !seenOwner[k.name] {
+			seenOwner[k.name] = true
+			owners = append(owners, k.name)
+		}
+	}
+
+	sortOwners(owners)
+
+	var sigs []dns.RR
+	for _, k := range order {
+		rrset := sets[k]
+		sort.Slice(rrset, func(i, j int) bool { return rrset[i].String() < rrset[j].String() })
+		for _, key := range cfg.Keys {
+			sig, err := signRRset(rrset, key, cfg)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs, owners, typesByOwner, nil
+}
+
+func signRRset(rrset []dns.RR, key *DNSSECKey, cfg *SignatureConfig) (*dns.RRSIG, error) {
+	ttl := rrset[0].Header().Ttl
+	if ttl == 0 {
+		ttl = cfg.DefaultSigTTL
+	}
+	sig := &dns.RRSIG{
+		Hdr: dns.RR_Header{
+			Name:   rrset[0].Header().Name,
+			Rrtype: dns.TypeRRSIG,
+			Class:  dns.ClassINET,
+			Ttl:    ttl,
+		},
+		Algorithm:  key.DNSKEY.Algorithm,
+		// OrigTtl must be set before Sign: Sign only fills it in from the
+		// covered record's own TTL when it's still zero, which is always
+		// true for the synthesized NSEC/NSEC3/NSEC3PARAM chain records, so
+		// leaving this unset would sign a zero OrigTtl for every chain RRSIG.
+		OrigTtl:    ttl,
+		Expiration: uint32(cfg.Expiration.Unix()),
+		Inception:  uint32(cfg.Inception.Unix()),
+		KeyTag:     key.DNSKEY.KeyTag(),
+		SignerName: cfg.SignerName,
+	}
+	if err := sig.Sign(key.Signer, rrset); err != nil {
+		return nil, fmt.Errorf("signing RRset %s/%s: %w", rrset[0].Header().Name, dns.TypeToString[rrset[0].Header().Rrtype], err)
+	}
+	return sig, nil
+}
+
+// sortOwners sorts owner names using the same label comparator the rest of
+// the zonefile is ordered with, so the NSEC/NSEC3 chain walks the zone in
+// the same order it's printed in.
+func sortOwners(owners []string) {
+	sort.Slice(owners, func(i, j int) bool { return zoneLabelLess(owners[i], owners[j]) })
+}
+
+// buildNSECChain synthesizes one NSEC record per authoritative owner name,
+// each covering the types present at that name and pointing to the next
+// name in sorted order (wrapping around to the first name at the apex).
+func buildNSECChain(owners []string, typesByOwner map[string][]uint16) []dns.RR {
+	var chain []dns.RR
+	for i, name := range owners {
+		next := owners[(i+1)%len(owners)]
+		bitmap := append(append([]uint16{}, typesByOwner[name]...), dns.TypeNSEC, dns.TypeRRSIG)
+		sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+		chain = append(chain, &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET},
+			NextDomain: next,
+			TypeBitMap: bitmap,
+		})
+	}
+	return chain
+}
+
+// buildNSEC3Chain mirrors buildNSECChain but hashes owner names per RFC
+// 5155, sorts the chain in hash order, and adds the NSEC3PARAM record.
+func buildNSEC3Chain(owners []string, typesByOwner map[string][]uint16, origin string, cfg *SignatureConfig) []dns.RR {
+	type hashedOwner struct {
+		hash string
+		name string
+	}
+	hashed := make([]hashedOwner, len(owners))
+	for i, name := range owners {
+		hashed[i] = hashedOwner{
+			hash: dns.HashName(name, dns.SHA1, cfg.Iterations, cfg.Salt),
+			name: name,
+		}
+	}
+	sort.Slice(hashed, func(i, j int) bool { return hashed[i].hash < hashed[j].hash })
+
+	optOutFlag := uint8(0)
+	if cfg.OptOut {
+		optOutFlag = 1
+	}
+
+	apex := origin + "."
+
+	var chain []dns.RR
+	for i, h := range hashed {
+		next := hashed[(i+1)%len(hashed)]
+		bitmap := append([]uint16{}, typesByOwner[h.name]...)
+		if h.name == apex {
+			// RFC 5155 7.1: the NSEC3 RR at the apex must indicate the
+			// presence of NSEC3PARAM, since typesByOwner was computed
+			// before the NSEC3PARAM record was added to the chain.
+			bitmap = append(bitmap, dns.TypeNSEC3PARAM)
+		}
+		bitmap = append(bitmap, dns.TypeRRSIG)
+		sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+		chain = append(chain, &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: h.hash + "." + origin + ".", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET},
+			Hash:       dns.SHA1,
+			Flags:      optOutFlag,
+			Iterations: cfg.Iterations,
+			SaltLength: uint8(len(cfg.Salt) / 2),
+			Salt:       cfg.Salt,
+			// HashLength is the raw SHA-1 digest length (20), not the
+			// base32-encoded hash string's length (32): the wire tag
+			// `size-base32:HashLength` uses it to size the decoded
+			// NextDomain bytes, and a real nameserver's zone scanner
+			// hardcodes 20 here too.
+			HashLength: sha1.Size,
+			NextDomain: next.hash,
+			TypeBitMap: bitmap,
+		})
+	}
+	chain = append(chain, &dns.NSEC3PARAM{
+		Hdr:        dns.RR_Header{Name: origin + ".", Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET},
+		Hash:       dns.SHA1,
+		Flags:      0,
+		Iterations: cfg.Iterations,
+		SaltLength: uint8(len(cfg.Salt) / 2),
+		Salt:       cfg.Salt,
+	})
+	return chain
+}
@@ -0,0 +1,236 @@
+package bind
+
+import (
+	"bytes"
+	"crypto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func mustParseRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("NewRR(%q): %v", s, err)
+	}
+	return rr
+}
+
+func newTestDNSSECKey(t *testing.T) *DNSSECKey {
+	t.Helper()
+	dnskey := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.RSASHA256,
+	}
+	priv, err := dnskey.Generate(1024)
+	if err != nil {
+		t.Fatalf("DNSKEY.Generate: %v", err)
+	}
+	return &DNSSECKey{DNSKEY: dnskey, Signer: priv.(crypto.Signer)}
+}
+
+func TestWriteSignedZoneFile(t *testing.T) {
+	key := newTestDNSSECKey(t)
+	records := []dns.RR{
+		mustParseRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 3600"),
+		mustParseRR(t, "example.com. 3600 IN NS ns1.example.com."),
+		mustParseRR(t, "www.example.com. 3600 IN A 10.0.0.1"),
+		mustParseRR(t, "mail.example.com. 3600 IN A 10.0.0.2"),
+	}
+	cfg := &SignatureConfig{
+		Inception:     time.Unix(1000, 0),
+		Expiration:    time.Unix(2000000000, 0),
+		SignerName:    "example.com.",
+		Keys:          []*DNSSECKey{key},
+		DefaultSigTTL: 3600,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSignedZoneFile(&buf, records, "example.com", 3600, cfg); err != nil {
+		t.Fatalf("WriteSignedZoneFile: %v", err)
+	}
+
+	zp := dns.NewZoneParser(strings.NewReader(buf.String()), "example.com.", "")
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("re-parsing signed zone: %v\n%s", err, buf.String())
+	}
+
+	// Every owner name must be covered by exactly one NSEC record.
+	nsecCount := map[string]int{}
+	var wwwRRset []dns.RR
+	var wwwSig *dns.RRSIG
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeNSEC {
+			nsecCount[rr.Header().Name]++
+		}
+		if rr.Header().Name == "www.example.com." && rr.Header().Rrtype == dns.TypeA {
+			wwwRRset = append(wwwRRset, rr)
+		}
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.Hdr.Name == "www.example.com." && sig.TypeCovered == dns.TypeA {
+			wwwSig = sig
+		}
+	}
+
+	for _, name := range []string{"example.com.", "www.example.com.", "mail.example.com."} {
+		if got := nsecCount[name]; got != 1 {
+			t.Errorf("owner %q covered by %d NSEC records, want exactly 1", name, got)
+		}
+	}
+
+	if wwwSig == nil {
+		t.Fatal("no RRSIG covering the www.example.com. A record")
+	}
+	if len(wwwRRset) == 0 {
+		t.Fatal("no A record found for www.example.com.")
+	}
+	if err := wwwSig.Verify(key.DNSKEY, wwwRRset); err != nil {
+		t.Errorf("RRSIG does not validate against the signing DNSKEY: %v", err)
+	}
+}
+
+func TestWriteSignedZoneFileChainRRSIGOrigTtl(t *testing.T) {
+	// The synthesized NSEC/NSEC3/NSEC3PARAM chain records never carry their
+	// own TTL, so their RRSIGs must fall back to cfg.DefaultSigTTL for
+	// OrigTtl too, not just the wire Hdr.Ttl -- otherwise Sign's "if unset"
+	// fallback signs a zero OrigTtl, which no validator accepts.
+	key := newTestDNSSECKey(t)
+	records := []dns.RR{
+		mustParseRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 3600"),
+		mustParseRR(t, "example.com. 3600 IN NS ns1.example.com."),
+		mustParseRR(t, "www.example.com. 3600 IN A 10.0.0.1"),
+	}
+	cfg := &SignatureConfig{
+		Inception:     time.Unix(1000, 0),
+		Expiration:    time.Unix(2000000000, 0),
+		SignerName:    "example.com.",
+		Keys:          []*DNSSECKey{key},
+		DefaultSigTTL: 9999,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSignedZoneFile(&buf, records, "example.com", 3600, cfg); err != nil {
+		t.Fatalf("WriteSignedZoneFile: %v", err)
+	}
+
+	zp := dns.NewZoneParser(strings.NewReader(buf.String()), "example.com.", "")
+	var nsecSig *dns.RRSIG
+	var nsec *dns.NSEC
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if n, ok := rr.(*dns.NSEC); ok && n.Hdr.Name == "example.com." {
+			nsec = n
+		}
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.Hdr.Name == "example.com." && sig.TypeCovered == dns.TypeNSEC {
+			nsecSig = sig
+		}
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("re-parsing signed zone: %v\n%s", err, buf.String())
+	}
+
+	if nsecSig == nil {
+		t.Fatal("no RRSIG covering the apex NSEC record")
+	}
+	if nsecSig.OrigTtl != cfg.DefaultSigTTL {
+		t.Errorf("NSEC RRSIG OrigTtl = %d, want %d (cfg.DefaultSigTTL)", nsecSig.OrigTtl, cfg.DefaultSigTTL)
+	}
+	if nsec == nil {
+		t.Fatal("no apex NSEC record found")
+	}
+	if err := nsecSig.Verify(key.DNSKEY, []dns.RR{nsec}); err != nil {
+		t.Errorf("NSEC RRSIG does not validate against the signing DNSKEY: %v", err)
+	}
+}
+
+func TestWriteSignedZoneFileNSEC3(t *testing.T) {
+	key := newTestDNSSECKey(t)
+	records := []dns.RR{
+		mustParseRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 3600"),
+		mustParseRR(t, "example.com. 3600 IN NS ns1.example.com."),
+		mustParseRR(t, "www.example.com. 3600 IN A 10.0.0.1"),
+		mustParseRR(t, "mail.example.com. 3600 IN A 10.0.0.2"),
+	}
+	cfg := &SignatureConfig{
+		Inception:     time.Unix(1000, 0),
+		Expiration:    time.Unix(2000000000, 0),
+		SignerName:    "example.com.",
+		Keys:          []*DNSSECKey{key},
+		DefaultSigTTL: 3600,
+		NSEC3:         true,
+		Salt:          "aabbcc",
+		Iterations:    2,
+		OptOut:        true,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSignedZoneFile(&buf, records, "example.com", 3600, cfg); err != nil {
+		t.Fatalf("WriteSignedZoneFile: %v", err)
+	}
+
+	zp := dns.NewZoneParser(strings.NewReader(buf.String()), "example.com.", "")
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("re-parsing NSEC3-signed zone: %v\n%s", err, buf.String())
+	}
+
+	var nsec3s []*dns.NSEC3
+	var param *dns.NSEC3PARAM
+	for _, rr := range rrs {
+		switch r := rr.(type) {
+		case *dns.NSEC3:
+			nsec3s = append(nsec3s, r)
+		case *dns.NSEC3PARAM:
+			param = r
+		}
+	}
+
+	if param == nil {
+		t.Fatal("no NSEC3PARAM record in signed zone")
+	}
+	if len(nsec3s) != 3 {
+		t.Fatalf("got %d NSEC3 records, want 3 (one per owner name)", len(nsec3s))
+	}
+
+	// Every NSEC3 record's wire encoding must round-trip: HashLength has
+	// to match the decoded NextDomain length (20 bytes for SHA-1), not the
+	// base32-encoded string's length (32), or unpacking fails.
+	apexHash := dns.HashName("example.com.", dns.SHA1, cfg.Iterations, cfg.Salt)
+	var apexNSEC3 *dns.NSEC3
+	for _, n := range nsec3s {
+		wire := make([]byte, dns.MaxMsgSize)
+		off, err := dns.PackRR(n, wire, 0, nil, false)
+		if err != nil {
+			t.Fatalf("PackRR(%v): %v", n, err)
+		}
+		if _, _, err := dns.UnpackRR(wire[:off], 0); err != nil {
+			t.Errorf("UnpackRR round-trip for %v: %v", n, err)
+		}
+		if strings.EqualFold(strings.SplitN(n.Hdr.Name, ".", 2)[0], apexHash) {
+			apexNSEC3 = n
+		}
+	}
+
+	if apexNSEC3 == nil {
+		t.Fatal("no NSEC3 record found for the zone apex")
+	}
+	hasParamBit := false
+	for _, typ := range apexNSEC3.TypeBitMap {
+		if typ == dns.TypeNSEC3PARAM {
+			hasParamBit = true
+		}
+	}
+	if !hasParamBit {
+		t.Errorf("apex NSEC3 type bitmap %v does not include NSEC3PARAM, violating RFC 5155 7.1", apexNSEC3.TypeBitMap)
+	}
+}
@@ -0,0 +1,173 @@
+// Alternate, format-agnostic output pipelines for generated zones: JSON,
+// YAML, and a normalized libdns.Record export for interop with the wider
+// libdns provider ecosystem.
+package bind
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+	"github.com/miekg/dns/dnsutil"
+	"gopkg.in/yaml.v3"
+)
+
+// zoneRecord is the common, serializable shape all alternate output formats
+// render: one object per RR, with Data carrying type-specific fields so
+// JSON/YAML consumers don't have to parse rr.String().
+type zoneRecord struct {
+	Name  string      `json:"name" yaml:"name"`
+	Ttl   uint32      `json:"ttl" yaml:"ttl"`
+	Class string      `json:"class" yaml:"class"`
+	Type  string      `json:"type" yaml:"type"`
+	Data  interface{} `json:"data" yaml:"data"`
+}
+
+// sortedRecords applies the same label/type ordering WriteZoneFile uses, so
+// every output format -- text, JSON, YAML -- agrees on record order and
+// diffs across runs stay stable.
+func sortedRecords(records []dns.RR, origin string, defaultTtl uint32) *zoneGenData {
+	z := &zoneGenData{
+		Origin:     origin,
+		DefaultTtl: defaultTtl,
+		Records:    append([]dns.RR{}, records...),
+	}
+	sort.Sort(z)
+	return z
+}
+
+// toZoneRecords converts a sorted zoneGenData into the common zoneRecord
+// shape shared by WriteZoneJSON and WriteZoneYAML.
+func toZoneRecords(z *zoneGenData) []zoneRecord {
+	out := make([]zoneRecord, 0, len(z.Records))
+	for _, rr := range z.Records {
+		hdr := rr.Header()
+		out = append(out, zoneRecord{
+			Name:  dnsutil.TrimDomainName(hdr.Name, z.Origin),
+			Ttl:   hdr.Ttl,
+			Class: dns.ClassToString[hdr.Class],
+			Type:  dns.TypeToString[hdr.Rrtype],
+			Data:  rrData(rr),
+		})
+	}
+	return out
+}
+
+// rrData extracts the type-specific fields of an RR for JSON/YAML export.
+// Types without a dedicated case fall back to their zone-file RDATA string.
+func rrData(rr dns.RR) interface{} {
+	switch t := rr.(type) {
+	case *dns.A:
+		return map[string]interface{}{"ip": t.A.String()}
+	case *dns.AAAA:
+		return map[string]interface{}{"ip": t.AAAA.String()}
+	case *dns.CNAME:
+		return map[string]interface{}{"target": t.Target}
+	case *dns.NS:
+		return map[string]interface{}{"target": t.Ns}
+	case *dns.PTR:
+		return map[string]interface{}{"target": t.Ptr}
+	case *dns.MX:
+		return map[string]interface{}{"preference": t.Preference, "exchange": t.Mx}
+	case *dns.SRV:
+		return map[string]interface{}{"priority": t.Priority, "weight": t.Weight, "port": t.Port, "target": t.Target}
+	case *dns.TXT:
+		return map[string]interface{}{"txt": t.Txt}
+	case *dns.CAA:
+		return map[string]interface{}{"flag": t.Flag, "tag": t.Tag, "value": t.Value}
+	case *dns.SOA:
+		return map[string]interface{}{
+			"ns":      t.Ns,
+			"mbox":    t.Mbox,
+			"serial":  t.Serial,
+			"refresh": t.Refresh,
+			"retry":   t.Retry,
+			"expire":  t.Expire,
+			"minttl":  t.Minttl,
+		}
+	default:
+		items := strings.SplitN(rr.String(), "\t", 5)
+		if len(items) == 5 {
+			return map[string]interface{}{"rdata": items[4]}
+		}
+		return map[string]interface{}{"rdata": rr.String()}
+	}
+}
+
+// WriteZoneJSON writes the zone as a JSON array of records, one object per
+// RR, in the same label/type order WriteZoneFile uses.
+func WriteZoneJSON(w io.Writer, records []dns.RR, origin string, defaultTtl uint32) error {
+	z := sortedRecords(records, origin, defaultTtl)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(toZoneRecords(z))
+}
+
+// WriteZoneYAML writes the zone as a YAML sequence of records, one document
+// entry per RR, in the same label/type order WriteZoneFile uses.
+func WriteZoneYAML(w io.Writer, records []dns.RR, origin string, defaultTtl uint32) error {
+	z := sortedRecords(records, origin, defaultTtl)
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(toZoneRecords(z))
+}
+
+// ExportLibdnsRecords normalizes the zone into []libdns.Record (Name, Type,
+// Value, TTL), the shape the libdns provider ecosystem expects, so
+// dnscontrol output can be handed directly to a libdns-based DNS manager.
+func ExportLibdnsRecords(records []dns.RR, origin string) []libdns.Record {
+	z := sortedRecords(records, origin, 0)
+	out := make([]libdns.Record, 0, len(z.Records))
+	for _, rr := range z.Records {
+		hdr := rr.Header()
+		out = append(out, libdns.Record{
+			Name:  dnsutil.TrimDomainName(hdr.Name, z.Origin),
+			Type:  dns.TypeToString[hdr.Rrtype],
+			Value: libdnsValue(rr),
+			TTL:   ttlDuration(hdr.Ttl),
+		})
+	}
+	return out
+}
+
+// libdnsValue renders an RR's RDATA as the single string libdns.Record.Value
+// expects, using the same per-type formatting a BIND zone file would, except
+// that TXT chunks are joined into one unquoted string.
+func libdnsValue(rr dns.RR) string {
+	switch t := rr.(type) {
+	case *dns.A:
+		return t.A.String()
+	case *dns.AAAA:
+		return t.AAAA.String()
+	case *dns.CNAME:
+		return t.Target
+	case *dns.NS:
+		return t.Ns
+	case *dns.PTR:
+		return t.Ptr
+	case *dns.MX:
+		return strconv.Itoa(int(t.Preference)) + " " + t.Mx
+	case *dns.SRV:
+		return fmt.Sprintf("%d %d %d %s", t.Priority, t.Weight, t.Port, t.Target)
+	case *dns.TXT:
+		return strings.Join(t.Txt, "")
+	default:
+		items := strings.SplitN(rr.String(), "\t", 5)
+		if len(items) == 5 {
+			return items[4]
+		}
+		return rr.String()
+	}
+}
+
+// ttlDuration converts a zonefile TTL (seconds) to the time.Duration
+// libdns.Record.TTL expects.
+func ttlDuration(ttl uint32) time.Duration {
+	return time.Duration(ttl) * time.Second
+}
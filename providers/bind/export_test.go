@@ -0,0 +1,86 @@
+package bind
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+func testExportRecords(t *testing.T) []dns.RR {
+	t.Helper()
+	return []dns.RR{
+		mustParseRR(t, "example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 3600"),
+		mustParseRR(t, "www.example.com. 300 IN A 10.0.0.1"),
+		mustParseRR(t, "mail.example.com. 300 IN MX 10 mx1.example.com."),
+	}
+}
+
+func TestWriteZoneJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteZoneJSON(&buf, testExportRecords(t), "example.com", 3600); err != nil {
+		t.Fatalf("WriteZoneJSON: %v", err)
+	}
+
+	var got []zoneRecord
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshaling JSON output: %v\n%s", err, buf.String())
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d records, want 3", len(got))
+	}
+
+	var foundA bool
+	for _, rec := range got {
+		if rec.Name == "www" && rec.Type == "A" {
+			foundA = true
+			data, ok := rec.Data.(map[string]interface{})
+			if !ok || data["ip"] != "10.0.0.1" {
+				t.Errorf("www A record data = %#v, want ip=10.0.0.1", rec.Data)
+			}
+		}
+	}
+	if !foundA {
+		t.Error("no JSON record found for www A")
+	}
+}
+
+func TestWriteZoneYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteZoneYAML(&buf, testExportRecords(t), "example.com", 3600); err != nil {
+		t.Fatalf("WriteZoneYAML: %v", err)
+	}
+
+	var got []zoneRecord
+	if err := yaml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshaling YAML output: %v\n%s", err, buf.String())
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d records, want 3", len(got))
+	}
+}
+
+func TestExportLibdnsRecords(t *testing.T) {
+	recs := ExportLibdnsRecords(testExportRecords(t), "example.com")
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3", len(recs))
+	}
+
+	var foundMX bool
+	for _, rec := range recs {
+		if rec.Name == "mail" && rec.Type == "MX" {
+			foundMX = true
+			if rec.Value != "10 mx1.example.com." {
+				t.Errorf("mail MX value = %q, want %q", rec.Value, "10 mx1.example.com.")
+			}
+			if rec.TTL.Seconds() != 300 {
+				t.Errorf("mail MX TTL = %v, want 300s", rec.TTL)
+			}
+		}
+	}
+	if !foundMX {
+		t.Error("no libdns.Record found for mail MX")
+	}
+}
@@ -27,14 +27,8 @@ func (z *zoneGenData) Less(i, j int) bool {
 	//fmt.Printf("DEBUG: z.Records=%#v\n", len(z.Records))
 	a, b := z.Records[i], z.Records[j]
 	//fmt.Printf("DEBUG: a=%#v b=%#v\n", a, b)
-	compA, compB := dnsutil.AddOrigin(a.Header().Name, z.Origin+"."), dnsutil.AddOrigin(b.Header().Name, z.Origin+".")
+	compA, compB := zoneOwnerKey(a, z.Origin), zoneOwnerKey(b, z.Origin)
 	if compA != compB {
-		if compA == z.Origin+"." {
-			compA = "@"
-		}
-		if compB == z.Origin+"." {
-			compB = "@"
-		}
 		return zoneLabelLess(compA, compB)
 	}
 	rrtypeA, rrtypeB := a.Header().Rrtype, b.Header().Rrtype
@@ -57,6 +51,17 @@ func (z *zoneGenData) Less(i, j int) bool {
 	return a.String() < b.String()
 }
 
+// zoneOwnerKey returns rr's owner name as zoneLabelLess expects to compare
+// it: fully qualified (origin is appended to a relative name), with the apex
+// rendered as "@".
+func zoneOwnerKey(rr dns.RR, origin string) string {
+	name := dnsutil.AddOrigin(rr.Header().Name, origin+".")
+	if name == origin+"." {
+		return "@"
+	}
+	return name
+}
+
 // WriteZoneFile writes a beautifully formatted zone file.
 func WriteZoneFile(w io.Writer, records []dns.RR, origin string, defaultTtl uint32) error {
 	// This function prioritizes beauty over efficiency.
@@ -69,69 +74,81 @@ func WriteZoneFile(w io.Writer, records []dns.RR, origin string, defaultTtl uint
 	// * $TTL is used to eliminate clutter.
 	// * "@" is used instead of the apex domain name.
 
-	z := &zoneGenData{
-		Origin:     origin,
-		DefaultTtl: defaultTtl,
-	}
-	z.Records = nil
-	for _, r := range records {
-		z.Records = append(z.Records, r)
-	}
+	z := sortedRecords(records, origin, defaultTtl)
 	return z.generateZoneFileHelper(w)
 }
 
-// generateZoneFileHelper creates a pretty zonefile.
+// generateZoneFileHelper sorts the zone's records and prints them.
 func (z *zoneGenData) generateZoneFileHelper(w io.Writer) error {
-
-	nameShortPrevious := ""
-
 	sort.Sort(z)
+	return z.printZoneFile(w)
+}
+
+// printZoneFile prints a beautifully formatted zonefile from z.Records,
+// which callers must have already sorted into the desired order.
+func (z *zoneGenData) printZoneFile(w io.Writer) error {
 	fmt.Fprintln(w, "$TTL", z.DefaultTtl)
+	return z.printRecords(w)
+}
+
+// printRecords prints z.Records in the pretty zonefile format, without the
+// leading $TTL line. Callers that need to interleave other directives
+// (e.g. $GENERATE) between $TTL and the records use this instead of
+// printZoneFile to avoid printing $TTL twice.
+func (z *zoneGenData) printRecords(w io.Writer) error {
+	nameShortPrevious := ""
 	for i, rr := range z.Records {
-		line := rr.String()
-		if line[0] == ';' {
-			continue
+		if err := printRecordLine(w, rr, z.Origin, z.DefaultTtl, &nameShortPrevious, i == 0); err != nil {
+			return err
 		}
-		hdr := rr.Header()
+	}
+	return nil
+}
 
-		items := strings.SplitN(line, "\t", 5)
-		if len(items) < 5 {
-			log.Fatalf("Too few items in: %v", line)
-		}
+// printRecordLine prints a single pretty zonefile line for rr, blanking the
+// owner name if it's the same as the previous line's (tracked via
+// nameShortPrevious) unless isFirst says this is the first line of the
+// zone. It's the line-printing primitive shared by zoneGenData.printZoneFile
+// and ZoneWriter, which drive it from a sorted slice and a merged stream of
+// on-disk runs respectively.
+func printRecordLine(w io.Writer, rr dns.RR, origin string, defaultTtl uint32, nameShortPrevious *string, isFirst bool) error {
+	line := rr.String()
+	if line[0] == ';' {
+		return nil
+	}
+	hdr := rr.Header()
 
-		// items[0]: name
-		nameFqdn := hdr.Name
-		nameShort := dnsutil.TrimDomainName(nameFqdn, z.Origin)
-		name := nameShort
-		if i > 0 && nameShort == nameShortPrevious {
-			name = ""
-		} else {
-			name = nameShort
-		}
-		nameShortPrevious = nameShort
+	items := strings.SplitN(line, "\t", 5)
+	if len(items) < 5 {
+		log.Fatalf("Too few items in: %v", line)
+	}
 
-		// items[1]: ttl
-		ttl := ""
-		if hdr.Ttl != z.DefaultTtl && hdr.Ttl != 0 {
-			ttl = items[1]
-		}
+	// items[0]: name
+	nameShort := dnsutil.TrimDomainName(hdr.Name, origin)
+	name := nameShort
+	if !isFirst && nameShort == *nameShortPrevious {
+		name = ""
+	}
+	*nameShortPrevious = nameShort
 
-		// items[2]: class
-		if hdr.Class != dns.ClassINET {
-			log.Fatalf("Unimplemented class=%v", items[2])
-		}
+	// items[1]: ttl
+	ttl := ""
+	if hdr.Ttl != defaultTtl && hdr.Ttl != 0 {
+		ttl = items[1]
+	}
 
-		// items[3]: type
-		typeStr := dns.TypeToString[hdr.Rrtype]
+	// items[2]: class
+	if hdr.Class != dns.ClassINET {
+		log.Fatalf("Unimplemented class=%v", items[2])
+	}
 
-		// items[4]: the remaining line
-		target := items[4]
-		//if typeStr == "TXT" {
-		//	fmt.Printf("generateZoneFileHelper.go: target=%#v\n", target)
-		//}
+	// items[3]: type
+	typeStr := dns.TypeToString[hdr.Rrtype]
 
-		fmt.Fprintln(w, formatLine([]int{10, 5, 2, 5, 0}, []string{name, ttl, "IN", typeStr, target}))
-	}
+	// items[4]: the remaining line
+	target := items[4]
+
+	fmt.Fprintln(w, formatLine([]int{10, 5, 2, 5, 0}, []string{name, ttl, "IN", typeStr, target}))
 	return nil
 }
 
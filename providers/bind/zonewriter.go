@@ -0,0 +1,230 @@
+// Streaming zone writer for very large zones. Instead of WriteZoneFile's
+// single sort.Sort over every record, ZoneWriter spills bounded batches of
+// records to sorted temp files as they arrive and k-way merges those runs
+// on Close, so the resident record count stays bounded by the batch size
+// and the number of open runs, not by the size of the zone.
+package bind
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/miekg/dns"
+)
+
+// zoneWriterBatchSize caps how many records ZoneWriter holds in memory
+// before sorting them and spilling them to a temp file as one run.
+const zoneWriterBatchSize = 10000
+
+// ZoneWriter incrementally renders a pretty zonefile. Add/AddBatch buffer
+// at most zoneWriterBatchSize records at a time; once that cap is hit, the
+// batch is sorted and spilled to an on-disk run. Close merges the runs (and
+// any remaining buffered records) in sorted order and streams the result to
+// w, so the full zone is never held in memory at once.
+type ZoneWriter struct {
+	w          io.Writer
+	origin     string
+	defaultTtl uint32
+
+	buffer []dns.RR
+	runs   []string
+}
+
+// NewZoneWriter returns a ZoneWriter that writes the zone to w when Close is
+// called, holding at most zoneWriterBatchSize records in memory at a time
+// instead of the whole zone like WriteZoneFile does.
+func NewZoneWriter(w io.Writer, origin string, defaultTtl uint32) *ZoneWriter {
+	return &ZoneWriter{
+		w:          w,
+		origin:     origin,
+		defaultTtl: defaultTtl,
+	}
+}
+
+// Add buffers a single record, spilling the current batch to disk if it has
+// reached zoneWriterBatchSize.
+func (zw *ZoneWriter) Add(rr dns.RR) {
+	zw.buffer = append(zw.buffer, rr)
+	if len(zw.buffer) >= zoneWriterBatchSize {
+		if err := zw.spill(); err != nil {
+			log.Fatalf("ZoneWriter: spilling run to disk: %v", err)
+		}
+	}
+}
+
+// AddBatch buffers a slice of records by calling Add for each one, as a
+// convenience for bulk loaders (e.g. the zonedb generator).
+func (zw *ZoneWriter) AddBatch(rrs []dns.RR) {
+	for _, rr := range rrs {
+		zw.Add(rr)
+	}
+}
+
+// spill sorts the current in-memory batch and writes it to a temp file, one
+// record per line, then drops the batch from memory.
+func (zw *ZoneWriter) spill() error {
+	if len(zw.buffer) == 0 {
+		return nil
+	}
+	sort.Slice(zw.buffer, func(i, j int) bool { return zoneWriterLess(zw.buffer[i], zw.buffer[j], zw.origin) })
+
+	f, err := os.CreateTemp("", "dnscontrol-zonewriter-*.run")
+	if err != nil {
+		return fmt.Errorf("ZoneWriter: creating run file: %w", err)
+	}
+	defer f.Close()
+	// Track the run as soon as it exists, so removeRuns cleans it up even if
+	// writing it fails part-way.
+	zw.runs = append(zw.runs, f.Name())
+	for _, rr := range zw.buffer {
+		// rr.String() is fully-qualified and self-describing (name, ttl,
+		// class, type, rdata), so it can be parsed back with dns.NewRR
+		// without needing origin context.
+		if _, err := fmt.Fprintln(f, rr.String()); err != nil {
+			return fmt.Errorf("ZoneWriter: writing run file: %w", err)
+		}
+	}
+
+	zw.buffer = nil
+	return nil
+}
+
+// Close spills any remaining buffered records, k-way merges every run, and
+// streams the merged, sorted zone to w. It always removes the temp run
+// files it created, even on error.
+func (zw *ZoneWriter) Close() error {
+	defer zw.removeRuns()
+	if err := zw.spill(); err != nil {
+		return err
+	}
+
+	readers := make([]*zoneWriterRun, 0, len(zw.runs))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, path := range zw.runs {
+		r, err := newZoneWriterRun(path)
+		if err != nil {
+			return err
+		}
+		readers = append(readers, r)
+	}
+
+	fmt.Fprintln(zw.w, "$TTL", zw.defaultTtl)
+	return zw.mergeRuns(readers)
+}
+
+func (zw *ZoneWriter) removeRuns() {
+	for _, path := range zw.runs {
+		os.Remove(path)
+	}
+	zw.runs = nil
+}
+
+// mergeRuns drives a min-heap over the current head record of every run,
+// repeatedly popping the overall minimum and printing it, so at most one
+// record per run is resident at any time -- not one per zone record.
+func (zw *ZoneWriter) mergeRuns(readers []*zoneWriterRun) error {
+	h := &zoneWriterHeap{origin: zw.origin}
+	for _, r := range readers {
+		if r.cur != nil {
+			h.runs = append(h.runs, r)
+		}
+	}
+	heap.Init(h)
+
+	nameShortPrevious := ""
+	isFirst := true
+	for h.Len() > 0 {
+		r := heap.Pop(h).(*zoneWriterRun)
+		if err := printRecordLine(zw.w, r.cur, zw.origin, zw.defaultTtl, &nameShortPrevious, isFirst); err != nil {
+			return err
+		}
+		isFirst = false
+
+		if err := r.advance(); err != nil {
+			return err
+		}
+		if r.cur != nil {
+			heap.Push(h, r)
+		}
+	}
+	return nil
+}
+
+// zoneWriterLess applies the exact ordering zoneGenData.Less uses (label,
+// then type, then the SOA/NS-first, MX-by-preference, A-by-IP rules), so a
+// zone merged run-by-run ends up in the same order WriteZoneFile produces.
+func zoneWriterLess(a, b dns.RR, origin string) bool {
+	z := &zoneGenData{Origin: origin, Records: []dns.RR{a, b}}
+	return z.Less(0, 1)
+}
+
+// zoneWriterRun reads one sorted, spilled batch back in, one record at a
+// time.
+type zoneWriterRun struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	cur     dns.RR
+}
+
+func newZoneWriterRun(path string) (*zoneWriterRun, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ZoneWriter: opening run file: %w", err)
+	}
+	r := &zoneWriterRun{file: f, scanner: bufio.NewScanner(f)}
+	if err := r.advance(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// advance reads the next record out of the run, or sets cur to nil at EOF.
+func (r *zoneWriterRun) advance() error {
+	if !r.scanner.Scan() {
+		r.cur = nil
+		return r.scanner.Err()
+	}
+	rr, err := dns.NewRR(r.scanner.Text())
+	if err != nil {
+		return fmt.Errorf("ZoneWriter: re-parsing spilled record: %w", err)
+	}
+	r.cur = rr
+	return nil
+}
+
+func (r *zoneWriterRun) Close() error {
+	return r.file.Close()
+}
+
+// zoneWriterHeap is a container/heap of runs, ordered by each run's current
+// head record.
+type zoneWriterHeap struct {
+	runs   []*zoneWriterRun
+	origin string
+}
+
+func (h *zoneWriterHeap) Len() int { return len(h.runs) }
+func (h *zoneWriterHeap) Less(i, j int) bool {
+	return zoneWriterLess(h.runs[i].cur, h.runs[j].cur, h.origin)
+}
+func (h *zoneWriterHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *zoneWriterHeap) Push(x interface{}) {
+	h.runs = append(h.runs, x.(*zoneWriterRun))
+}
+func (h *zoneWriterHeap) Pop() interface{} {
+	old := h.runs
+	n := len(old)
+	item := old[n-1]
+	h.runs = old[:n-1]
+	return item
+}
@@ -0,0 +1,53 @@
+package bind
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// benchRecords generates n distinct A records under example.com, for
+// comparing WriteZoneFile's single-slice approach against ZoneWriter's
+// bounded-batch-and-merge approach at scale.
+func benchRecords(n int) []dns.RR {
+	records := make([]dns.RR, n)
+	for i := 0; i < n; i++ {
+		records[i] = &dns.A{
+			Hdr: dns.RR_Header{
+				Name:   "host" + strconv.Itoa(i) + ".example.com.",
+				Rrtype: dns.TypeA,
+				Class:  dns.ClassINET,
+				Ttl:    300,
+			},
+			A: net.IPv4(10, byte(i>>16), byte(i>>8), byte(i)),
+		}
+	}
+	return records
+}
+
+func BenchmarkWriteZoneFile1M(b *testing.B) {
+	records := benchRecords(1_000_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := WriteZoneFile(io.Discard, records, "example.com", 300); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkZoneWriter1M(b *testing.B) {
+	records := benchRecords(1_000_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		zw := NewZoneWriter(io.Discard, "example.com", 300)
+		zw.AddBatch(records)
+		if err := zw.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package bind
+
+import (
+	"bytes"
+	"testing"
+)
+
+// assertZoneWriterMatches checks that ZoneWriter, fed n records in a single
+// AddBatch call, produces byte-identical output to WriteZoneFile given the
+// same records.
+func assertZoneWriterMatches(t *testing.T, n int) {
+	t.Helper()
+	records := benchRecords(n)
+
+	var want bytes.Buffer
+	if err := WriteZoneFile(&want, records, "example.com", 300); err != nil {
+		t.Fatalf("WriteZoneFile: %v", err)
+	}
+
+	var got bytes.Buffer
+	zw := NewZoneWriter(&got, "example.com", 300)
+	zw.AddBatch(records)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("ZoneWriter.Close: %v", err)
+	}
+
+	if got.String() != want.String() {
+		t.Errorf("ZoneWriter output for %d records differs from WriteZoneFile's:\n--- got ---\n%s\n--- want ---\n%s", n, got.String(), want.String())
+	}
+}
+
+func TestZoneWriterMatchesWriteZoneFileSingleRun(t *testing.T) {
+	// Fewer than zoneWriterBatchSize records: Add never spills, and Close
+	// writes the single buffered batch out as the only run to merge.
+	assertZoneWriterMatches(t, 100)
+}
+
+func TestZoneWriterMatchesWriteZoneFileMultiRun(t *testing.T) {
+	// More than zoneWriterBatchSize records, and not an exact multiple of
+	// it, so Close has to merge several spilled runs plus one partial
+	// trailing batch.
+	assertZoneWriterMatches(t, zoneWriterBatchSize*2+1)
+}